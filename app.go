@@ -0,0 +1,312 @@
+// Package hl7push scans, watches, and serves HL7v2 messages into the GCP
+// Healthcare API. cmd/hl7push wires this package's Run* entry points to a
+// cobra/viper CLI; RunScanOnce, RunWatch, and ServeMLLP correspond to its
+// push, watch, and serve subcommands respectively.
+package hl7push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	hl7 "github.com/radpartners/go-hl7"
+	"github.com/spinatose/hl7push/mllp"
+	"github.com/spinatose/hl7push/retry"
+	"github.com/spinatose/hl7push/transform"
+	"github.com/spinatose/hl7push/watcher"
+)
+
+type MsgVals struct {
+	SendingFacility string `json:"sending_facility" yaml:"sending_facility"`
+}
+
+// newHcapiClient builds the Sink hl7push sends every message through: the
+// hcapi Client wrapped with retry/backoff and dead-letter handling so a
+// transient GCP error doesn't silently drop a message, and with cfg's
+// configured Transform chain so every message is mutated the same way
+// regardless of which run mode sent it. If cfg.Routes is set, the
+// returned Sink is a transform.Router that picks one of several such
+// Sinks by the message's sending facility, so one watcher or MLLP
+// listener can fan a multi-tenant feed out to separate GCP HL7 stores.
+func newHcapiClient(ctx context.Context, cfg Config) (Sink, error) {
+	transforms, err := buildTransforms(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build transform chain: %w", err)
+	}
+
+	defaultSink, err := newRoutedSink(ctx, cfg, transforms)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Routes) == 0 {
+		return defaultSink, nil
+	}
+
+	routes := make(map[string]transform.Sink, len(cfg.Routes))
+	for facility, route := range cfg.Routes {
+		routeCfg := cfg
+		routeCfg.ProjectID = route.ProjectID
+		routeCfg.LocationID = route.LocationID
+		routeCfg.DatasetID = route.DatasetID
+		routeCfg.HL7StoreID = route.HL7StoreID
+
+		sink, err := newRoutedSink(ctx, routeCfg, transforms)
+		if err != nil {
+			return nil, fmt.Errorf("unable to build sink for route %q: %w", facility, err)
+		}
+		routes[facility] = sink
+	}
+
+	return transform.Router{Routes: routes, Default: defaultSink}, nil
+}
+
+// newRoutedSink builds a single hcapi Client for cfg, wrapped with
+// retry/backoff, dead-letter handling, and transforms. It is called once
+// for cfg's top-level GCP destination and once more per cfg.Routes entry.
+func newRoutedSink(ctx context.Context, cfg Config, transforms []Transform) (Sink, error) {
+	cli, err := NewClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var sink Sink = newRetryingSink(cli, cfg.MaxRetryDuration, cfg.DeadLetterDir)
+
+	if cfg.MirrorMLLPAddr != "" {
+		mirrored, err := newMirrorSink(sink, cfg.MirrorMLLPAddr, cfg.RequestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("unable to set up mirror mllp sink: %w", err)
+		}
+		sink = mirrored
+	}
+
+	// transforms wrap everything else so both the primary hcapi send and
+	// the mirror (if configured) receive the same redacted/rewritten
+	// bytes -- the mirror must never see pre-transform PHI.
+	if len(transforms) > 0 {
+		sink = &chainSink{transforms: transforms, sink: sink}
+	}
+
+	return sink, nil
+}
+
+// buildTransforms assembles the Transform chain described by cfg: a
+// templated Z-segment append, PID redaction, and MSH-3..6 rewriting, each
+// only included if cfg enables it. Order matters -- redaction and MSH
+// rewriting run on the original message, the Z-segment is appended last.
+func buildTransforms(cfg Config) ([]Transform, error) {
+	var chain []Transform
+
+	if cfg.RedactionRulesFile != "" {
+		rules, err := transform.LoadRedactionRules(cfg.RedactionRulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load redaction rules from %s: %w", cfg.RedactionRulesFile, err)
+		}
+		chain = append(chain, transform.PIDRedactor{Rules: rules})
+	}
+
+	if cfg.MSHMappingFile != "" {
+		rules, err := transform.LoadMSHRewriteRules(cfg.MSHMappingFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load MSH mapping from %s: %w", cfg.MSHMappingFile, err)
+		}
+		chain = append(chain, transform.MSHRewriter{Rules: rules})
+	}
+
+	if cfg.ZSegmentTemplate != "" {
+		chain = append(chain, transform.ZSegmentTemplate{Template: cfg.ZSegmentTemplate})
+	}
+
+	return chain, nil
+}
+
+// RunScanOnce walks cfg.HL7Dir to completion, cfg.Loopit times, in the
+// calling goroutine. This is the original, pre-watcher behavior, kept
+// available as the `push` subcommand. ctx is canceled on SIGINT/SIGTERM so
+// an in-flight send can be aborted between files.
+func RunScanOnce(ctx context.Context, cfg Config) error {
+	if cfg.HL7Dir == "" {
+		return errors.New("missing hl7_dir")
+	}
+	if cfg.Loopit <= 0 {
+		return nil
+	}
+
+	cli, err := newHcapiClient(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create hcapi client: %w", err)
+	}
+
+	src := &fileSource{root: cfg.HL7Dir, retainSent: cfg.RetainSent}
+	for i := 0; i < cfg.Loopit; i++ {
+		fmt.Println()
+		fmt.Printf("Loop #%v in folder '%s'\n", i+1, cfg.HL7Dir)
+		fmt.Println()
+		if err := src.Run(ctx, cli); err != nil {
+			fmt.Printf("error scanning %s: %s\n", cfg.HL7Dir, err)
+		}
+	}
+
+	return nil
+}
+
+// RunWatch replaces fixed-count polling with an fsnotify-based watch over
+// cfg.HL7Dir, dispatching newly created `.hl7` files to a pool of worker
+// goroutines. All workers share the same hcapi client, so the client's
+// rate limiter enforces a global QPS ceiling rather than a per-worker one.
+// This is the `watch` subcommand.
+func RunWatch(ctx context.Context, cfg Config) error {
+	if cfg.HL7Dir == "" {
+		return errors.New("missing hl7_dir")
+	}
+
+	cli, err := newHcapiClient(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create hcapi client: %w", err)
+	}
+
+	w, err := watcher.New(cfg.HL7Dir, func(ctx context.Context, dir, name string) error {
+		return hl7WebStoreInstance(ctx, dir, name, cli, cfg.RetainSent)
+	}, watcher.Config{Workers: cfg.WorkerCount})
+	if err != nil {
+		return fmt.Errorf("unable to start watcher on %s: %w", cfg.HL7Dir, err)
+	}
+
+	fmt.Printf("watcher: watching %s\n", cfg.HL7Dir)
+	return w.Run(ctx)
+}
+
+// ServeMLLP starts the inbound MLLP listener on cfg.MLLPBindAddr,
+// forwarding every received message to the hcapi client and writing its
+// ACK back on the connection. ctx governs the lifetime of in-flight sends
+// so shutdown can drain them. This is the `serve` subcommand.
+func ServeMLLP(ctx context.Context, cfg Config) error {
+	if cfg.MLLPBindAddr == "" {
+		return errors.New("missing mllp_bind_addr")
+	}
+
+	cli, err := newHcapiClient(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to get new hcapi client for mllp server: %w", err)
+	}
+
+	srv := mllp.NewServer(ctx, cfg.MLLPBindAddr, cli)
+	fmt.Printf("mllp: listening on %s\n", cfg.MLLPBindAddr)
+	return srv.ListenAndServe()
+}
+
+// fileSource is a Source that recursively walks root looking for `.hl7`
+// files and delivers each one to the Sink it is run with.
+type fileSource struct {
+	root       string
+	retainSent bool
+}
+
+func (f *fileSource) Run(ctx context.Context, sink Sink) error {
+	return f.scanDirectory(ctx, f.root, sink)
+}
+
+func (f *fileSource) scanDirectory(ctx context.Context, path string, sink Sink) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		filepath := path + "/" + file.Name()
+		if file.IsDir() {
+			fmt.Println(filepath + " is a subdirectory. Moving into it for processing....")
+			if err := f.scanDirectory(ctx, filepath, sink); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.Contains(file.Name(), ".hl7") {
+			if err := hl7WebStoreInstance(ctx, path, file.Name(), sink, f.retainSent); err != nil {
+				fmt.Printf("error: %s\n", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func hl7WebStoreInstance(ctx context.Context, hl7Path, hl7File string, sink Sink, retainSent bool) error {
+	// must run thru hl7 parse in order to remove invalid new line chars
+	msg, err := hl7.ParseFile(hl7Path+"/"+hl7File, true)
+	if err != nil {
+		return err
+	}
+
+	// Get SendingFacility from header of message
+	hdr, err := hl7.GetMessageHeader(*msg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("Sending Facility for Message is %s\n", hdr.SendingFacility)
+	fmt.Println()
+
+	hl7Data := msg.Raw()
+
+	data, pth, err := sink.Send(ctx, hl7Data)
+	if err != nil {
+		return fmt.Errorf("unable to send msg to sink: %w", err)
+	}
+
+	fmt.Printf("message successfully stored at: %s\n", pth)
+
+	err = checkAck(data)
+	if err != nil {
+		return err
+	}
+
+	// sink.Send applies cfg's configured Transform chain (Z-segment
+	// append, redaction, MSH rewriting) before the message reaches hcapi,
+	// so retainSent keeps, once the send has succeeded, whatever was
+	// actually parsed here rather than a transformed copy.
+	if retainSent {
+		if err := os.MkdirAll("./tmp", 0o755); err != nil {
+			return fmt.Errorf("unable to create retain directory: %w", err)
+		}
+		if err := ioutil.WriteFile("./tmp/"+hl7File, hl7Data, 0o644); err != nil {
+			fmt.Printf("unable to retain sent copy of %s: %s\n", hl7File, err)
+		}
+	}
+
+	return nil
+}
+
+func checkAck(b []byte) error {
+	ack, err := hl7.ParseMessage(b, true)
+	if err != nil {
+		return err
+	}
+
+	switch hl7.MessageType(*ack) {
+	case "ACK":
+		// ack received - pass in ack to analytics
+		// if mack != nil {
+		// 	if s.anacli != nil {
+		// 		s.anacli.AcknowledgementReceived(orig, mack)
+		// 	}
+		// }
+		return nil
+	case "NACK":
+		return retry.Permanent(errors.New("receiving system returned nack"))
+	default:
+		return errors.New("invalid ack response")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/spinatose/hl7push"
+)
+
+const envPrefix = "HL7PUSH"
+
+// defaults are the built-in values used when a setting is absent from the
+// config file, the environment, and the command line.
+var defaults = map[string]interface{}{
+	"credential":           "",
+	"project":              "",
+	"location":             "",
+	"dataset":              "",
+	"store":                "",
+	"rate_limit":           0,
+	"request_timeout":      30 * time.Second,
+	"hl7_dir":              "./hl7in",
+	"retain_sent":          false,
+	"loopit":               1,
+	"worker_count":         0,
+	"mllp_bind_addr":       ":2575",
+	"max_retry_duration":   5 * time.Minute,
+	"dead_letter_dir":      "./deadletter",
+	"mirror_mllp_addr":     "",
+	"z_segment_template":   "ZAC|{{time}}",
+	"redaction_rules_file": "",
+	"msh_mapping_file":     "",
+}
+
+// loadConfig layers configuration sources for cmd, in increasing order of
+// precedence: built-in defaults, the YAML file named by --config (if any),
+// environment variables prefixed HL7PUSH_, then the command's own flags.
+// Every flag is bound to its matching viper key so an explicitly-set flag
+// always wins, while an unset one falls through to the lower layers.
+func loadConfig(cmd *cobra.Command) (hl7push.Config, error) {
+	v := viper.New()
+
+	for key, val := range defaults {
+		v.SetDefault(key, val)
+	}
+
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	if cfgFile, _ := cmd.Flags().GetString("config"); cfgFile != "" {
+		v.SetConfigFile(cfgFile)
+		if err := v.ReadInConfig(); err != nil {
+			return hl7push.Config{}, err
+		}
+	}
+
+	if err := v.BindPFlags(cmd.Flags()); err != nil {
+		return hl7push.Config{}, err
+	}
+
+	var cfg hl7push.Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return hl7push.Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// bindConfigFlags registers every hl7push.Config field as a flag on cmd so
+// it can be set from the command line, named to match the YAML/env keys
+// (e.g. --request_timeout, HL7PUSH_REQUEST_TIMEOUT, request_timeout).
+func bindConfigFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.String("credential", defaults["credential"].(string), "path to a GCP service account credentials file")
+	f.String("project", defaults["project"].(string), "GCP project ID")
+	f.String("location", defaults["location"].(string), "GCP location")
+	f.String("dataset", defaults["dataset"].(string), "Healthcare API dataset")
+	f.String("store", defaults["store"].(string), "Healthcare API HL7v2 store")
+	f.Int("rate_limit", defaults["rate_limit"].(int), "maximum sends per second; 0 is unlimited")
+	f.Duration("request_timeout", defaults["request_timeout"].(time.Duration), "per-call deadline applied when the caller's context has none")
+	f.String("hl7_dir", defaults["hl7_dir"].(string), "directory scanned or watched for .hl7 files")
+	f.Bool("retain_sent", defaults["retain_sent"].(bool), "keep the parsed, pre-transform copy under ./tmp after a successful send")
+	f.Int("loopit", defaults["loopit"].(int), "number of times the push command scans hl7_dir")
+	f.Int("worker_count", defaults["worker_count"].(int), "workers draining the watch command's queue; 0 means GOMAXPROCS")
+	f.String("mllp_bind_addr", defaults["mllp_bind_addr"].(string), "address the serve command listens on for inbound MLLP")
+	f.Duration("max_retry_duration", defaults["max_retry_duration"].(time.Duration), "total time to keep retrying a send before dead-lettering it")
+	f.String("dead_letter_dir", defaults["dead_letter_dir"].(string), "where sends that exhaust retries or fail permanently are written")
+	f.String("mirror_mllp_addr", defaults["mirror_mllp_addr"].(string), "optional outbound MLLP address every sent message is also mirrored to; empty disables mirroring")
+	f.String("z_segment_template", defaults["z_segment_template"].(string), "pipe-delimited Z-segment appended to every outbound message; empty disables it")
+	f.String("redaction_rules_file", defaults["redaction_rules_file"].(string), "optional JSON file listing which PID fields to redact before sending")
+	f.String("msh_mapping_file", defaults["msh_mapping_file"].(string), "optional JSON file mapping a sending facility to MSH-3..6 rewrite values")
+}
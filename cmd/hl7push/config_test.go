@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cmd := newPushCmd()
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	if cfg.HL7Dir != "./hl7in" {
+		t.Fatalf("expected default hl7_dir, got %q", cfg.HL7Dir)
+	}
+	if cfg.RequestTimeout != 30*time.Second {
+		t.Fatalf("expected default request_timeout, got %v", cfg.RequestTimeout)
+	}
+}
+
+func TestLoadConfigYAMLOverridesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgFile, []byte("hl7_dir: /from/yaml\nloopit: 7\n"), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	// --config is the root command's persistent flag, so the push command
+	// only has it once attached to the root and its flags are parsed.
+	cmd, _, err := newRootCmd().Find([]string{"push"})
+	if err != nil {
+		t.Fatalf("find push command: %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--config", cfgFile}); err != nil {
+		t.Fatalf("set config flag: %v", err)
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	if cfg.HL7Dir != "/from/yaml" {
+		t.Fatalf("expected hl7_dir from yaml, got %q", cfg.HL7Dir)
+	}
+	if cfg.Loopit != 7 {
+		t.Fatalf("expected loopit from yaml, got %d", cfg.Loopit)
+	}
+}
+
+func TestLoadConfigEnvOverridesYAML(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgFile, []byte("hl7_dir: /from/yaml\n"), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	t.Setenv("HL7PUSH_HL7_DIR", "/from/env")
+
+	cmd, _, err := newRootCmd().Find([]string{"push"})
+	if err != nil {
+		t.Fatalf("find push command: %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--config", cfgFile}); err != nil {
+		t.Fatalf("set config flag: %v", err)
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	if cfg.HL7Dir != "/from/env" {
+		t.Fatalf("expected hl7_dir from env to win over yaml, got %q", cfg.HL7Dir)
+	}
+}
+
+func TestLoadConfigFlagOverridesEnv(t *testing.T) {
+	t.Setenv("HL7PUSH_HL7_DIR", "/from/env")
+
+	cmd := newPushCmd()
+	if err := cmd.Flags().Set("hl7_dir", "/from/flag"); err != nil {
+		t.Fatalf("set hl7_dir flag: %v", err)
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	if cfg.HL7Dir != "/from/flag" {
+		t.Fatalf("expected hl7_dir from flag to win over env, got %q", cfg.HL7Dir)
+	}
+}
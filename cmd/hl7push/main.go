@@ -0,0 +1,96 @@
+// Command hl7push scans, watches, or serves HL7v2 messages into the GCP
+// Healthcare API. See the push, watch, and serve subcommands.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/spinatose/hl7push"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "hl7push",
+		Short: "Scan, watch, or serve HL7v2 messages into the GCP Healthcare API",
+	}
+
+	root.PersistentFlags().String("config", "", "path to a YAML config file")
+
+	root.AddCommand(newPushCmd(), newWatchCmd(), newServeCmd())
+	return root
+}
+
+func newPushCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Scan hl7_dir for .hl7 files and send them, loopit times, then exit",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := shutdownContext()
+			defer cancel()
+			return hl7push.RunScanOnce(ctx, cfg)
+		},
+	}
+	bindConfigFlags(cmd)
+	return cmd
+}
+
+func newWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch hl7_dir for new .hl7 files and send each as it arrives",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := shutdownContext()
+			defer cancel()
+			return hl7push.RunWatch(ctx, cfg)
+		},
+	}
+	bindConfigFlags(cmd)
+	return cmd
+}
+
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Listen for inbound MLLP connections and forward received messages",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := shutdownContext()
+			defer cancel()
+			return hl7push.ServeMLLP(ctx, cfg)
+		},
+	}
+	bindConfigFlags(cmd)
+	return cmd
+}
+
+// shutdownContext returns a context canceled on SIGINT/SIGTERM, plus the
+// stop func signal.NotifyContext requires callers to release (otherwise the
+// signal handler it registers leaks), so a running subcommand can drain
+// in-flight sends before exiting.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,93 @@
+package hl7push
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// deadLetterRecord is the sidecar written alongside a dead-lettered message
+// describing why it failed and how many attempts were made.
+type deadLetterRecord struct {
+	MessageControlID string    `json:"message_control_id"`
+	Attempts         int       `json:"attempts"`
+	LastError        string    `json:"last_error"`
+	FirstAttemptAt   time.Time `json:"first_attempt_at"`
+	FailedAt         time.Time `json:"failed_at"`
+}
+
+// writeDeadLetter persists a message that failed non-retryably, or
+// exhausted its retries, to dir alongside a `.err.json` sidecar describing
+// the failure.
+func writeDeadLetter(dir string, data []byte, attempts int, firstAttempt time.Time, lastErr error) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	name := messageControlID(data)
+	if name == "" {
+		name = fmt.Sprintf("unknown-%d", time.Now().UnixNano())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, name+".hl7"), data, 0o644); err != nil {
+		return err
+	}
+
+	rec := deadLetterRecord{
+		MessageControlID: name,
+		Attempts:         attempts,
+		LastError:        lastErr.Error(),
+		FirstAttemptAt:   firstAttempt,
+		FailedAt:         time.Now(),
+	}
+
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, name+".err.json"), b, 0o644)
+}
+
+// messageControlID extracts MSH-10 (message control ID) from a raw HL7
+// message without requiring a full parse, so a malformed message can still
+// be dead-lettered under a useful filename.
+func messageControlID(data []byte) string {
+	msh := firstSegment(data, "MSH")
+	if msh == "" {
+		return ""
+	}
+
+	fields := strings.Split(msh, "|")
+	const controlIDField = 9 // MSH-1 is the field separator itself, so index 9 is MSH-10
+	if len(fields) <= controlIDField {
+		return ""
+	}
+
+	return sanitizeFilename(strings.TrimSpace(fields[controlIDField]))
+}
+
+func firstSegment(data []byte, name string) string {
+	for _, line := range strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == '\r' || r == '\n'
+	}) {
+		if strings.HasPrefix(line, name) {
+			return line
+		}
+	}
+	return ""
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
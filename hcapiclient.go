@@ -1,4 +1,4 @@
-package main
+package hl7push
 
 import (
 	"context"
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"strings"
+	"time"
 
 	"go.uber.org/ratelimit"
 	"golang.org/x/oauth2"
@@ -24,42 +26,104 @@ const (
 	apiFormatHeader = "X-GOOG-API-FORMAT-VERSION"
 )
 
+// Config is the complete, flattened configuration for hl7push: GCP
+// Healthcare API connection details plus the operational settings for
+// every run mode (file scan, watch, serve). It is populated by layering a
+// YAML file, environment variables, and CLI flags (see cmd/hl7push), with
+// CLI flags taking precedence.
 type Config struct {
-	Credential string `json:"credential" config:"credential"`
-	ProjectID  string `json:"project" config:"project,description=GCP Project ID"`
-	LocationID string `json:"location" config:"location,description=GCP Location"`
-	DatasetID  string `json:"dataset" config:"dataset,description=HC API Dataset"`
-	HL7StoreID string `json:"store" config:"store,description=HC API HL7 Store"`
-	RateLimit  int    `json:"rate_limit" config:"rate_limit"`
+	Credential     string        `json:"credential" mapstructure:"credential" config:"credential"`
+	ProjectID      string        `json:"project" mapstructure:"project" config:"project,description=GCP Project ID"`
+	LocationID     string        `json:"location" mapstructure:"location" config:"location,description=GCP Location"`
+	DatasetID      string        `json:"dataset" mapstructure:"dataset" config:"dataset,description=HC API Dataset"`
+	HL7StoreID     string        `json:"store" mapstructure:"store" config:"store,description=HC API HL7 Store"`
+	RateLimit      int           `json:"rate_limit" mapstructure:"rate_limit" config:"rate_limit"`
+	RequestTimeout time.Duration `json:"request_timeout" mapstructure:"request_timeout" config:"request_timeout,description=Per-call deadline applied when the caller's context has none"`
+
+	HL7Dir           string        `json:"hl7_dir" mapstructure:"hl7_dir" config:"hl7_dir,description=Directory scanned or watched for .hl7 files"`
+	RetainSent       bool          `json:"retain_sent" mapstructure:"retain_sent" config:"retain_sent,description=Keep the parsed pre-transform copy under ./tmp after a successful send"`
+	Loopit           int           `json:"loopit" mapstructure:"loopit" config:"loopit,description=Number of times the push command scans hl7_dir"`
+	WorkerCount      int           `json:"worker_count" mapstructure:"worker_count" config:"worker_count,description=Workers draining the watch command's queue; 0 means GOMAXPROCS"`
+	MLLPBindAddr     string        `json:"mllp_bind_addr" mapstructure:"mllp_bind_addr" config:"mllp_bind_addr,description=Address the serve command listens on for inbound MLLP"`
+	MaxRetryDuration time.Duration `json:"max_retry_duration" mapstructure:"max_retry_duration" config:"max_retry_duration,description=Total time to keep retrying a send before dead-lettering it"`
+	DeadLetterDir    string        `json:"dead_letter_dir" mapstructure:"dead_letter_dir" config:"dead_letter_dir,description=Where sends that exhaust retries or fail permanently are written"`
+	MirrorMLLPAddr   string        `json:"mirror_mllp_addr" mapstructure:"mirror_mllp_addr" config:"mirror_mllp_addr,description=Optional outbound MLLP address every sent message is also mirrored to. Empty disables mirroring"`
+
+	ZSegmentTemplate   string `json:"z_segment_template" mapstructure:"z_segment_template" config:"z_segment_template,description=Pipe-delimited Z-segment appended to every outbound message; supports {{time}}, {{uuid}}, and {{env:NAME}} placeholders. Empty disables it"`
+	RedactionRulesFile string `json:"redaction_rules_file" mapstructure:"redaction_rules_file" config:"redaction_rules_file,description=Optional JSON file listing which PID fields to redact before sending"`
+	MSHMappingFile     string `json:"msh_mapping_file" mapstructure:"msh_mapping_file" config:"msh_mapping_file,description=Optional JSON file mapping a sending facility to MSH-3..6 rewrite values"`
+
+	// Routes maps a sending facility (MSH-4) to the GCP Healthcare API
+	// destination its messages should be ingested into, instead of the
+	// top-level ProjectID/LocationID/DatasetID/HL7StoreID. A facility with
+	// no entry falls back to that top-level destination. Every route
+	// shares the top-level Credential, RateLimit, RequestTimeout,
+	// MaxRetryDuration, and DeadLetterDir.
+	Routes map[string]RouteConfig `json:"routes" mapstructure:"routes" config:"routes,description=Sending facility to GCP Healthcare API destination, for multi-tenant fan-out"`
 }
 
+// RouteConfig is one Routes entry: the GCP Healthcare API destination for
+// a single sending facility.
+type RouteConfig struct {
+	ProjectID  string `json:"project" mapstructure:"project"`
+	LocationID string `json:"location" mapstructure:"location"`
+	DatasetID  string `json:"dataset" mapstructure:"dataset"`
+	HL7StoreID string `json:"store" mapstructure:"store"`
+}
+
+// Validate reports every missing or invalid field at once, rather than
+// bailing out on the first one, so operators fix all of a bad deployment
+// config in a single pass.
 func (c Config) Validate() error {
 	fmt.Println("validating config for hcapi client...")
 
+	var errs []error
+
 	if c.ProjectID == "" {
-		return errors.New("missing project id")
+		errs = append(errs, errors.New("missing project id"))
 	}
-
 	if c.LocationID == "" {
-		return errors.New("missing location id")
+		errs = append(errs, errors.New("missing location id"))
 	}
-
 	if c.DatasetID == "" {
-		return errors.New("missing dataset id")
+		errs = append(errs, errors.New("missing dataset id"))
 	}
-
 	if c.HL7StoreID == "" {
-		return errors.New("missing hl7 store id")
+		errs = append(errs, errors.New("missing hl7 store id"))
+	}
+	if c.RequestTimeout < 0 {
+		errs = append(errs, errors.New("request_timeout must not be negative"))
+	}
+	if c.WorkerCount < 0 {
+		errs = append(errs, errors.New("worker_count must not be negative"))
+	}
+	if c.MaxRetryDuration < 0 {
+		errs = append(errs, errors.New("max_retry_duration must not be negative"))
+	}
+	// hl7_dir and mllp_bind_addr are each required by only some run modes
+	// (push/watch and serve respectively), so whether they're required is
+	// validated by RunScanOnce/RunWatch/ServeMLLP in app.go, not here.
+	// mllp_bind_addr's format is checked here since it's unconditional
+	// whenever it's set at all.
+	if c.MLLPBindAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MLLPBindAddr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid mllp_bind_addr %q: %w", c.MLLPBindAddr, err))
+		}
+	}
+	for facility, route := range c.Routes {
+		if route.ProjectID == "" || route.LocationID == "" || route.DatasetID == "" || route.HL7StoreID == "" {
+			errs = append(errs, fmt.Errorf("route %q is missing one of project/location/dataset/store", facility))
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 type Client interface {
-	Send(data []byte) ([]byte, string, error)
-	GetByID(id string) (*healthcare.Message, error)
-	Get(path string) (*healthcare.Message, error)
-	List() (*healthcare.ListMessagesResponse, error)
+	Send(ctx context.Context, data []byte) ([]byte, string, error)
+	GetByID(ctx context.Context, id string) (*healthcare.Message, error)
+	Get(ctx context.Context, path string) (*healthcare.Message, error)
+	List(ctx context.Context) (*healthcare.ListMessagesResponse, error)
 }
 
 type client struct {
@@ -96,7 +160,22 @@ func NewClient(ctx context.Context, config Config) (Client, error) {
 	return c, nil
 }
 
-func (c *client) Send(data []byte) ([]byte, string, error) {
+// withDeadline returns a context individual calls can use for the outbound
+// request: if ctx already carries a deadline (the caller overrode it), that
+// deadline is honored as-is; otherwise the client's configured
+// RequestTimeout is applied as the default. The returned cancel func must
+// always be called by the caller.
+func (c *client) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.RequestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.config.RequestTimeout)
+}
+
+func (c *client) Send(ctx context.Context, data []byte) ([]byte, string, error) {
 	resultpath := ""
 	c.limiter.Take()
 
@@ -109,7 +188,9 @@ func (c *client) Send(data []byte) ([]byte, string, error) {
 		},
 	}
 
-	ctx := context.Background()
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+
 	storeName := genStoreName(c.config)
 	ingest := c.store.Messages.Ingest(storeName, req)
 	ingest.Header().Add(apiFormatHeader, "2")
@@ -130,11 +211,13 @@ func (c *client) Send(data []byte) ([]byte, string, error) {
 	return res, resultpath, nil
 }
 
-func (c *client) List() (*healthcare.ListMessagesResponse, error) {
+func (c *client) List(ctx context.Context) (*healthcare.ListMessagesResponse, error) {
 	c.limiter.Take()
 	fmt.Println("list messages on hcapi client")
 
-	ctx := context.Background()
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+
 	storeName := genStoreName(c.config)
 
 	list := c.store.Messages.List(storeName)
@@ -143,18 +226,19 @@ func (c *client) List() (*healthcare.ListMessagesResponse, error) {
 	return list.Context(ctx).Do()
 }
 
-func (c *client) GetByID(id string) (*healthcare.Message, error) {
+func (c *client) GetByID(ctx context.Context, id string) (*healthcare.Message, error) {
 	path := fmt.Sprintf("%s/messages/%s", genStoreName(c.config), id)
-	return c.Get(path)
+	return c.Get(ctx, path)
 }
 
-func (c *client) Get(path string) (*healthcare.Message, error) {
+func (c *client) Get(ctx context.Context, path string) (*healthcare.Message, error) {
 	c.limiter.Take()
 
 	get := c.store.Messages.Get(path)
 	get.Header().Add(apiFormatHeader, "2")
 
-	ctx := context.Background()
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
 	return get.Context(ctx).Do()
 }
 
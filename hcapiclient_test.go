@@ -0,0 +1,43 @@
+package hl7push
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithDeadlineAppliesDefaultTimeout(t *testing.T) {
+	c := &client{config: Config{RequestTimeout: 50 * time.Millisecond}}
+
+	ctx, cancel := c.withDeadline(context.Background())
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); !ok {
+		t.Fatal("expected a deadline to be applied")
+	}
+}
+
+func TestWithDeadlineHonorsCallerOverride(t *testing.T) {
+	c := &client{config: Config{RequestTimeout: 50 * time.Millisecond}}
+
+	callerCtx, callerCancel := context.WithTimeout(context.Background(), time.Hour)
+	defer callerCancel()
+
+	ctx, cancel := c.withDeadline(callerCtx)
+	defer cancel()
+
+	if ctx != callerCtx {
+		t.Fatal("expected caller's context with its own deadline to be used as-is")
+	}
+}
+
+func TestWithDeadlineNoopWhenUnconfigured(t *testing.T) {
+	c := &client{config: Config{}}
+
+	ctx, cancel := c.withDeadline(context.Background())
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("expected no deadline when RequestTimeout is unset")
+	}
+}
@@ -0,0 +1,54 @@
+package mllp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Client is an outbound MLLP client that can be used as an alternative sink
+// to hcapi so operators can mirror the same message to a downstream
+// receiver.
+type Client struct {
+	Addr    string
+	Timeout time.Duration
+
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Dial opens a TCP connection to addr for use as an MLLP client. timeout, if
+// non-zero, bounds both the dial and each subsequent Send round-trip.
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("mllp: dial %s: %w", addr, err)
+	}
+	return &Client{Addr: addr, Timeout: timeout, conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// Send frames data as an MLLP message, writes it to the connection, and
+// returns the unframed ACK/NACK the receiver responds with.
+func (c *Client) Send(data []byte) ([]byte, error) {
+	if c.Timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.Timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := c.conn.Write(Frame(data)); err != nil {
+		return nil, fmt.Errorf("mllp: write to %s: %w", c.Addr, err)
+	}
+
+	ack, err := readFrame(c.r)
+	if err != nil {
+		return nil, fmt.Errorf("mllp: read ack from %s: %w", c.Addr, err)
+	}
+	return ack, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
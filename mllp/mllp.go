@@ -0,0 +1,76 @@
+// Package mllp implements the Minimum Lower Layer Protocol (MLLP) framing
+// used to exchange HL7v2 messages over TCP with EHR interface engines.
+//
+// A frame is a raw HL7 message wrapped with a leading start-of-block byte
+// (0x0B) and a trailing end-of-block/carriage-return pair (0x1C 0x0D):
+//
+//	<VT> hl7 message bytes <FS><CR>
+package mllp
+
+import (
+	"bufio"
+	"errors"
+)
+
+const (
+	// StartBlock is the MLLP start-of-block marker (VT).
+	StartBlock = 0x0B
+	// EndBlock is the MLLP end-of-block marker (FS).
+	EndBlock = 0x1C
+	// CarriageReturn terminates an MLLP frame after EndBlock.
+	CarriageReturn = 0x0D
+)
+
+var (
+	errFrameTooShort = errors.New("mllp: frame too short")
+	errMissingStart  = errors.New("mllp: frame missing start block")
+	errMissingEnd    = errors.New("mllp: frame missing end block")
+)
+
+// Frame wraps msg with MLLP start/end block markers for transmission.
+func Frame(msg []byte) []byte {
+	framed := make([]byte, 0, len(msg)+3)
+	framed = append(framed, StartBlock)
+	framed = append(framed, msg...)
+	framed = append(framed, EndBlock, CarriageReturn)
+	return framed
+}
+
+// Unframe strips the MLLP start/end block markers from a complete frame,
+// returning the raw HL7 message bytes.
+func Unframe(frame []byte) ([]byte, error) {
+	if len(frame) < 3 {
+		return nil, errFrameTooShort
+	}
+	if frame[0] != StartBlock {
+		return nil, errMissingStart
+	}
+	if frame[len(frame)-2] != EndBlock || frame[len(frame)-1] != CarriageReturn {
+		return nil, errMissingEnd
+	}
+	return frame[1 : len(frame)-2], nil
+}
+
+// readFrame reads a single MLLP frame from r, blocking until a complete
+// frame (start block through the trailing carriage return) has arrived.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	if _, err := r.ReadBytes(StartBlock); err != nil {
+		return nil, err
+	}
+
+	body, err := r.ReadBytes(EndBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	cr, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if cr != CarriageReturn {
+		return nil, errMissingEnd
+	}
+
+	// body includes the trailing EndBlock byte; strip it.
+	return body[:len(body)-1], nil
+}
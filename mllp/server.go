@@ -0,0 +1,121 @@
+package mllp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Sender delivers a raw HL7 message to its destination and returns the raw
+// HL7 ACK/NACK bytes the destination responded with. hcapi's Client
+// satisfies this interface, which is all the MLLP server depends on. ctx
+// is the server's shutdown context, canceled on SIGINT/SIGTERM so an
+// in-flight send can be aborted cleanly.
+type Sender interface {
+	Send(ctx context.Context, data []byte) (ack []byte, path string, err error)
+}
+
+// Server is an inbound MLLP listener. It accepts framed HL7 messages from
+// EHR interface engines, hands the raw bytes to Sender, and writes the
+// resulting ACK back on the same connection with MLLP framing.
+type Server struct {
+	Addr   string
+	Sender Sender
+	// Ctx governs the lifetime of in-flight Sender.Send calls. Canceling
+	// it does not itself stop Serve -- call Close for that -- but lets
+	// shutdown propagate to a hung send. Defaults to context.Background.
+	Ctx context.Context
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// NewServer returns a Server that will listen on addr and forward received
+// messages to sender, propagating ctx to every Sender.Send call so a
+// canceled shutdown context can abort an in-flight send.
+func NewServer(ctx context.Context, addr string, sender Sender) *Server {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Server{Addr: addr, Sender: sender, Ctx: ctx}
+}
+
+// ListenAndServe opens a TCP listener on s.Addr and serves connections
+// until the listener is closed or s.Ctx is canceled.
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("mllp: listen on %s: %w", s.Addr, err)
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and handles each on its own goroutine. It
+// blocks until l is closed or s.Ctx is canceled, at which point it returns
+// nil.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	go func() {
+		<-s.Ctx.Done()
+		s.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if isClosedError(err) {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close shuts down the listener, causing Serve to return.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readFrame(r)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Printf("mllp: error reading frame from %s: %v\n", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		ack, path, err := s.Sender.Send(s.Ctx, msg)
+		if err != nil {
+			fmt.Printf("mllp: error sending message from %s: %v\n", conn.RemoteAddr(), err)
+			return
+		}
+		fmt.Printf("mllp: forwarded message from %s, stored at %s\n", conn.RemoteAddr(), path)
+
+		if _, err := conn.Write(Frame(ack)); err != nil {
+			fmt.Printf("mllp: error writing ack to %s: %v\n", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+func isClosedError(err error) bool {
+	return errors.Is(err, net.ErrClosed)
+}
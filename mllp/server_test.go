@@ -0,0 +1,70 @@
+package mllp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+type echoSender struct{}
+
+func (echoSender) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	return []byte("ACK:" + string(data)), "stores/test/messages/1", nil
+}
+
+func TestServerRoundTripAck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	srv := NewServer(context.Background(), l.Addr().String(), echoSender{})
+	go func() {
+		if err := srv.Serve(l); err != nil {
+			t.Logf("serve exited: %v", err)
+		}
+	}()
+	defer srv.Close()
+
+	cli, err := Dial(l.Addr().String(), 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer cli.Close()
+
+	ack, err := cli.Send([]byte("MSH|^~\\&|..."))
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	want := "ACK:MSH|^~\\&|..."
+	if string(ack) != want {
+		t.Fatalf("ack = %q, want %q", ack, want)
+	}
+}
+
+func TestFrameUnframeRoundTrip(t *testing.T) {
+	msg := []byte("MSH|^~\\&|FOO")
+	framed := Frame(msg)
+
+	got, err := Unframe(framed)
+	if err != nil {
+		t.Fatalf("unframe: %v", err)
+	}
+	if string(got) != string(msg) {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+}
+
+func TestUnframeErrors(t *testing.T) {
+	if _, err := Unframe([]byte{0x00}); err == nil {
+		t.Fatal("expected error for short frame")
+	}
+	if _, err := Unframe([]byte{0x00, 'a', EndBlock, CarriageReturn}); err == nil {
+		t.Fatal("expected error for missing start block")
+	}
+	if _, err := Unframe([]byte{StartBlock, 'a', 0x00, CarriageReturn}); err == nil {
+		t.Fatal("expected error for missing end block")
+	}
+}
@@ -0,0 +1,57 @@
+package hl7push
+
+import "context"
+
+// Source produces HL7 messages and drives them through a Sink until it is
+// exhausted or ctx is canceled. The file scanner (fileSource) is the only
+// Source; the watcher and the MLLP listener do not implement this
+// interface and are run directly by RunWatch and ServeMLLP.
+type Source interface {
+	Run(ctx context.Context, sink Sink) error
+}
+
+// Transform mutates a message between Source and Sink, e.g. appending a
+// Z-segment or rewriting routing fields.
+type Transform interface {
+	Apply(data []byte) ([]byte, error)
+}
+
+// Sink delivers a message to its destination and returns the raw ACK/NACK
+// bytes along with the storage path, if any. hcapi's Client and mllp's
+// outbound Client (via mllpSink) both satisfy this interface. ctx carries
+// the caller's deadline and is canceled on shutdown so in-flight sends can
+// drain cleanly.
+type Sink interface {
+	Send(ctx context.Context, data []byte) ([]byte, string, error)
+}
+
+// Pipeline wires a Source through zero or more Transforms to a Sink.
+type Pipeline struct {
+	Source     Source
+	Transforms []Transform
+	Sink       Sink
+}
+
+// Run drives the pipeline's Source until it is exhausted or ctx is
+// canceled.
+func (p *Pipeline) Run(ctx context.Context) error {
+	return p.Source.Run(ctx, &chainSink{transforms: p.Transforms, sink: p.Sink})
+}
+
+// chainSink applies a Transform chain in front of a Sink so Sources never
+// need to know about transforms.
+type chainSink struct {
+	transforms []Transform
+	sink       Sink
+}
+
+func (c *chainSink) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	for _, t := range c.transforms {
+		var err error
+		data, err = t.Apply(data)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return c.sink.Send(ctx, data)
+}
@@ -0,0 +1,70 @@
+// Package retry implements exponential backoff with jitter and error
+// classification for retrying failed hcapi sends.
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff generates a sequence of exponentially increasing, jittered
+// delays until MaxElapsedTime has passed since Reset.
+type Backoff struct {
+	InitialInterval     time.Duration
+	MaxInterval         time.Duration
+	MaxElapsedTime      time.Duration
+	Multiplier          float64
+	RandomizationFactor float64
+
+	start   time.Time
+	current time.Duration
+}
+
+// DefaultBackoff returns the backoff policy used across hl7push: a 100ms
+// initial delay capped at 30s, growing by 1.5x each attempt with 50%
+// jitter, bounded by maxElapsed total.
+func DefaultBackoff(maxElapsed time.Duration) *Backoff {
+	return &Backoff{
+		InitialInterval:     100 * time.Millisecond,
+		MaxInterval:         30 * time.Second,
+		MaxElapsedTime:      maxElapsed,
+		Multiplier:          1.5,
+		RandomizationFactor: 0.5,
+	}
+}
+
+// Reset starts a new retry cycle. Call before the first attempt.
+func (b *Backoff) Reset() {
+	b.start = time.Now()
+	b.current = b.InitialInterval
+}
+
+// Next returns the delay before the next attempt and whether the caller is
+// still within MaxElapsedTime. Reset is called implicitly on first use.
+func (b *Backoff) Next() (time.Duration, bool) {
+	if b.start.IsZero() {
+		b.Reset()
+	}
+	if b.MaxElapsedTime > 0 && time.Since(b.start) >= b.MaxElapsedTime {
+		return 0, false
+	}
+
+	delay := jitter(b.current, b.RandomizationFactor)
+
+	b.current = time.Duration(float64(b.current) * b.Multiplier)
+	if b.MaxInterval > 0 && b.current > b.MaxInterval {
+		b.current = b.MaxInterval
+	}
+
+	return delay, true
+}
+
+func jitter(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		return d
+	}
+	delta := factor * float64(d)
+	lo := float64(d) - delta
+	hi := float64(d) + delta
+	return time.Duration(lo + (hi-lo)*rand.Float64())
+}
@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Retryable reports whether err represents a transient failure worth
+// retrying: a googleapi 429/5xx response, any net.Error (a timeout, a
+// refused or reset connection, ...), a DNS error, a context deadline, or
+// an error whose text mentions "timeout". A googleapi 4xx other than 429
+// is not retryable, and neither is anything wrapped with Permanent, such
+// as an HL7 NACK.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, errPermanent) {
+		return false
+	}
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return gerr.Code == 429 || gerr.Code >= 500
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "timeout")
+}
+
+var errPermanent = errors.New("permanent error")
+
+// Permanent marks err as non-retryable regardless of its underlying type,
+// e.g. an HL7 NACK, so Retryable returns false for it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type permanentError struct{ err error }
+
+func (p *permanentError) Error() string        { return p.err.Error() }
+func (p *permanentError) Unwrap() error        { return p.err }
+func (p *permanentError) Is(target error) bool { return target == errPermanent }
@@ -0,0 +1,75 @@
+package hl7push
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spinatose/hl7push/retry"
+)
+
+// retryingSink wraps a Sink with exponential backoff and routes messages
+// that are non-retryable or that exhaust maxRetryDuration to a dead-letter
+// directory, instead of dropping them on the first failure.
+type retryingSink struct {
+	sink             Sink
+	maxRetryDuration time.Duration
+	deadLetterDir    string
+}
+
+// newRetryingSink wraps sink so failed sends are retried with backoff and,
+// on permanent failure, written to deadLetterDir (disabled if empty).
+func newRetryingSink(sink Sink, maxRetryDuration time.Duration, deadLetterDir string) *retryingSink {
+	return &retryingSink{sink: sink, maxRetryDuration: maxRetryDuration, deadLetterDir: deadLetterDir}
+}
+
+func (r *retryingSink) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	bo := retry.DefaultBackoff(r.maxRetryDuration)
+	firstAttempt := time.Now()
+
+	var (
+		ack     []byte
+		path    string
+		lastErr error
+		attempt int
+	)
+
+retryLoop:
+	for {
+		attempt++
+		ack, path, lastErr = r.sink.Send(ctx, data)
+		if lastErr == nil {
+			lastErr = checkAck(ack)
+		}
+		if lastErr == nil {
+			return ack, path, nil
+		}
+
+		if !retry.Retryable(lastErr) {
+			break
+		}
+
+		delay, ok := bo.Next()
+		if !ok {
+			break
+		}
+
+		fmt.Printf("retry: attempt %d failed (%v), retrying in %s\n", attempt, lastErr, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			lastErr = ctx.Err()
+			break retryLoop
+		}
+	}
+
+	fmt.Printf("retry: giving up after %d attempt(s): %v\n", attempt, lastErr)
+
+	if r.deadLetterDir != "" {
+		if dlErr := writeDeadLetter(r.deadLetterDir, data, attempt, firstAttempt, lastErr); dlErr != nil {
+			fmt.Printf("retry: failed to write dead letter: %v\n", dlErr)
+		}
+	}
+
+	return ack, path, lastErr
+}
@@ -0,0 +1,103 @@
+package hl7push
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spinatose/hl7push/retry"
+)
+
+// scriptedSink is a Sink stub that returns a pre-programmed sequence of
+// results, one per call, repeating the last entry once exhausted.
+type scriptedSink struct {
+	calls      int
+	totalCalls int
+	results    []scriptedResult
+}
+
+type scriptedResult struct {
+	ack []byte
+	err error
+}
+
+func (s *scriptedSink) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	s.totalCalls++
+	r := s.results[s.calls]
+	if s.calls < len(s.results)-1 {
+		s.calls++
+	}
+	return r.ack, "stores/test/messages/1", r.err
+}
+
+func ackMessage(t *testing.T) []byte {
+	t.Helper()
+	return []byte("MSH|^~\\&|APP|FAC|APP2|FAC2|20230101||ACK|MSG00001|P|2.3\rMSA|AA|MSG00001\r")
+}
+
+func TestRetryingSinkRetriesTransientErrors(t *testing.T) {
+	sink := &scriptedSink{results: []scriptedResult{
+		{err: errors.New("dial tcp: i/o timeout")},
+		{err: errors.New("dial tcp: i/o timeout")},
+		{ack: ackMessage(t)},
+	}}
+
+	rs := newRetryingSink(sink, 5*time.Second, "")
+	_, path, err := rs.Send(context.Background(), []byte("MSH|..."))
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if path != "stores/test/messages/1" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+	if sink.calls != 2 {
+		t.Fatalf("expected 3 attempts (calls index 2), got index %d", sink.calls)
+	}
+}
+
+func TestRetryingSinkDeadLettersPermanentFailure(t *testing.T) {
+	dir := t.TempDir()
+	sink := &scriptedSink{results: []scriptedResult{
+		{err: retry.Permanent(errors.New("googleapi: Error 400: bad request"))},
+	}}
+
+	rs := newRetryingSink(sink, 5*time.Second, dir)
+	if _, _, err := rs.Send(context.Background(), ackNackMessage()); err == nil {
+		t.Fatal("expected Send to return the permanent error")
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.err.json"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected exactly one dead-lettered sidecar, got %d", len(matches))
+	}
+}
+
+func TestRetryingSinkDeadLettersExhaustedRetries(t *testing.T) {
+	dir := t.TempDir()
+	sink := &scriptedSink{results: []scriptedResult{
+		{err: errors.New("dial tcp: i/o timeout")},
+	}}
+
+	rs := newRetryingSink(sink, 50*time.Millisecond, dir)
+	if _, _, err := rs.Send(context.Background(), ackNackMessage()); err == nil {
+		t.Fatal("expected Send to return the last retryable error after exhausting retries")
+	}
+
+	if sink.totalCalls <= 1 {
+		t.Fatalf("expected more than one attempt before giving up, got %d", sink.totalCalls)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "MSG00002.hl7")); err != nil {
+		t.Fatalf("expected dead-lettered message file: %v", err)
+	}
+}
+
+func ackNackMessage() []byte {
+	return []byte("MSH|^~\\&|APP|FAC|APP2|FAC2|20230101||ADT^A01|MSG00002|P|2.3\r")
+}
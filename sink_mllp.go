@@ -0,0 +1,104 @@
+package hl7push
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spinatose/hl7push/mllp"
+)
+
+// mllpSink adapts an outbound mllp.Client to the Sink interface so a
+// downstream MLLP receiver can be used in place of (or alongside) hcapi,
+// mirroring the same message to a secondary system.
+type mllpSink struct {
+	cli *mllp.Client
+}
+
+// newMLLPSink dials addr and returns a Sink that forwards messages to it
+// using MLLP framing.
+func newMLLPSink(addr string, timeout time.Duration) (*mllpSink, error) {
+	cli, err := mllp.Dial(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &mllpSink{cli: cli}, nil
+}
+
+func (s *mllpSink) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	ack, err := s.cli.Send(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return ack, "", nil
+}
+
+// Close releases the underlying MLLP connection.
+func (s *mllpSink) Close() error {
+	return s.cli.Close()
+}
+
+// mirrorSink sends every message to primary first, then best-effort mirrors
+// it, MLLP-framed, to a secondary receiver. primary's ack and path are
+// authoritative; a mirror failure is logged but never fails the Send, since
+// losing the mirror copy shouldn't block ingestion into the system of
+// record.
+//
+// mirrorSink is shared across goroutines (watch mode runs WorkerCount
+// workers against one Sink), but mllpSink holds a single TCP connection
+// that can't be written/read concurrently, so mu serializes every mirror
+// attempt. A failed send closes and drops the connection so the next Send
+// redials rather than failing forever on a stale one.
+type mirrorSink struct {
+	primary Sink
+	addr    string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	mirror *mllpSink
+}
+
+// newMirrorSink wraps primary so every message sent through it is also
+// mirrored to addr. The mirror connection is dialed lazily on first use (and
+// redialed after a failure) rather than here, so a downstream receiver that
+// isn't up yet doesn't prevent startup.
+func newMirrorSink(primary Sink, addr string, timeout time.Duration) (Sink, error) {
+	return &mirrorSink{primary: primary, addr: addr, timeout: timeout}, nil
+}
+
+func (s *mirrorSink) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	ack, path, err := s.primary.Send(ctx, data)
+	if err != nil {
+		return ack, path, err
+	}
+
+	if mErr := s.mirrorSend(data); mErr != nil {
+		fmt.Printf("mirror: failed to mirror message to %s: %v\n", s.addr, mErr)
+	}
+
+	return ack, path, nil
+}
+
+// mirrorSend serializes access to the single shared mirror connection,
+// redialing it first if a prior send closed it.
+func (s *mirrorSink) mirrorSend(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mirror == nil {
+		mirror, err := newMLLPSink(s.addr, s.timeout)
+		if err != nil {
+			return fmt.Errorf("unable to dial mirror mllp address %s: %w", s.addr, err)
+		}
+		s.mirror = mirror
+	}
+
+	if _, _, err := s.mirror.Send(context.Background(), data); err != nil {
+		s.mirror.Close()
+		s.mirror = nil
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package transform
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// MSH field positions, counting from the field separator the way
+// hl7push's own MSH-10 extraction does (index == field number - 1).
+const (
+	mshSendingApp        = 2
+	mshSendingFacility   = 3
+	mshReceivingApp      = 4
+	mshReceivingFacility = 5
+)
+
+// MSHRewriteRule replaces the sending/receiving application and facility
+// fields (MSH-3 through MSH-6) of an outbound message.
+type MSHRewriteRule struct {
+	SendingApp        string `json:"sending_app"`
+	SendingFacility   string `json:"sending_facility"`
+	ReceivingApp      string `json:"receiving_app"`
+	ReceivingFacility string `json:"receiving_facility"`
+}
+
+// LoadMSHRewriteRules reads a JSON file at path mapping a message's
+// current MSH-4 (sending facility) to the MSHRewriteRule that should
+// replace it.
+func LoadMSHRewriteRules(path string) (map[string]MSHRewriteRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules map[string]MSHRewriteRule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// MSHRewriter rewrites MSH-3/4/5/6 according to Rules, keyed by the
+// message's existing MSH-4 (sending facility) at the time of the call.
+// Messages whose sending facility has no entry in Rules pass through
+// unchanged, so the same binary can relay for multiple upstreams that
+// need different values on the wire.
+type MSHRewriter struct {
+	Rules map[string]MSHRewriteRule
+}
+
+func (m MSHRewriter) Apply(data []byte) ([]byte, error) {
+	sep := fieldSeparator(data)
+
+	segments := splitSegments(data)
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "MSH") {
+			continue
+		}
+
+		fields := strings.Split(seg, sep)
+		if len(fields) <= mshSendingFacility {
+			continue
+		}
+
+		rule, ok := m.Rules[fields[mshSendingFacility]]
+		if !ok {
+			continue
+		}
+
+		setField(fields, mshSendingApp, rule.SendingApp)
+		setField(fields, mshSendingFacility, rule.SendingFacility)
+		setField(fields, mshReceivingApp, rule.ReceivingApp)
+		setField(fields, mshReceivingFacility, rule.ReceivingFacility)
+		segments[i] = strings.Join(fields, sep)
+	}
+
+	return joinSegments(segments), nil
+}
+
+// setField replaces fields[index] with value, unless value is empty, so a
+// rule that only sets some of MSH-3..6 leaves the rest of the message's
+// original fields alone instead of blanking them.
+func setField(fields []string, index int, value string) {
+	if value == "" {
+		return
+	}
+	if index < len(fields) {
+		fields[index] = value
+	}
+}
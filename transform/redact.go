@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// PID field positions. Unlike MSH, where MSH-1 is the field separator
+// itself (so index == field number - 1), PID-1 is a real field, so every
+// other segment's index matches its field number directly.
+const (
+	pidName = 5
+	pidDOB  = 7
+	pidSSN  = 19
+)
+
+// RedactionRules selects which PID fields PIDRedactor masks and what to
+// mask them with. Loaded from a JSON rules file so lower environments can
+// redact PHI without a recompile.
+type RedactionRules struct {
+	Name bool   `json:"name"`
+	SSN  bool   `json:"ssn"`
+	DOB  bool   `json:"dob"`
+	Mask string `json:"mask"`
+}
+
+// LoadRedactionRules reads RedactionRules from a JSON file at path.
+func LoadRedactionRules(path string) (RedactionRules, error) {
+	var rules RedactionRules
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+// PIDRedactor masks configured PID fields (name, SSN, DOB) for lower
+// environments.
+type PIDRedactor struct {
+	Rules RedactionRules
+}
+
+func (p PIDRedactor) Apply(data []byte) ([]byte, error) {
+	mask := p.Rules.Mask
+	if mask == "" {
+		mask = "REDACTED"
+	}
+
+	sep := fieldSeparator(data)
+
+	segments := splitSegments(data)
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "PID") {
+			continue
+		}
+
+		fields := strings.Split(seg, sep)
+		if p.Rules.Name {
+			maskField(fields, pidName, mask)
+		}
+		if p.Rules.DOB {
+			maskField(fields, pidDOB, mask)
+		}
+		if p.Rules.SSN {
+			maskField(fields, pidSSN, mask)
+		}
+		segments[i] = strings.Join(fields, sep)
+	}
+
+	return joinSegments(segments), nil
+}
+
+func maskField(fields []string, index int, mask string) {
+	if index < len(fields) {
+		fields[index] = mask
+	}
+}
@@ -0,0 +1,56 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Sink delivers a raw HL7 message to its destination, returning the raw
+// ACK/NACK bytes and a storage path. Defined locally, rather than
+// importing hl7push, so this package stays a leaf dependency -- the same
+// reason mllp.Sender and watcher.Handler don't reference hl7push types.
+type Sink interface {
+	Send(ctx context.Context, data []byte) ([]byte, string, error)
+}
+
+// Router dispatches a message to one of several Sinks keyed by the
+// message's MSH-4 (sending facility), so one watcher or MLLP listener can
+// fan a multi-tenant feed out to separate GCP HL7 stores, each reachable
+// through its own Sink.
+type Router struct {
+	// Routes maps a sending facility to the Sink that should receive its
+	// messages.
+	Routes map[string]Sink
+	// Default is used when a message's sending facility has no entry in
+	// Routes. A nil Default makes an unmatched facility an error.
+	Default Sink
+}
+
+func (r Router) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	facility := sendingFacility(data)
+
+	sink, ok := r.Routes[facility]
+	if !ok {
+		sink = r.Default
+	}
+	if sink == nil {
+		return nil, "", fmt.Errorf("transform: no route for sending facility %q", facility)
+	}
+
+	return sink.Send(ctx, data)
+}
+
+func sendingFacility(data []byte) string {
+	msh := firstSegment(data, "MSH")
+	if msh == "" {
+		return ""
+	}
+
+	fields := strings.Split(msh, fieldSeparator(data))
+	if len(fields) <= mshSendingFacility {
+		return ""
+	}
+
+	return strings.TrimSpace(fields[mshSendingFacility])
+}
@@ -0,0 +1,43 @@
+package transform
+
+import "strings"
+
+// splitSegments splits a raw HL7 message on its \r segment terminator.
+// Unlike strings.FieldsFunc, strings.Split keeps empty segments and the
+// final (possibly empty) element after a trailing terminator, so
+// joinSegments can reassemble data byte-for-byte when no transformer
+// changes anything. hl7.ParseFile already normalizes a message's line
+// endings to \r before hl7push hands it to a transform, so \r is the only
+// terminator transforms need to handle here.
+func splitSegments(data []byte) []string {
+	return strings.Split(string(data), "\r")
+}
+
+// joinSegments is splitSegments' inverse.
+func joinSegments(segments []string) []byte {
+	return []byte(strings.Join(segments, "\r"))
+}
+
+// firstSegment returns the first segment in data whose name matches, or ""
+// if none is found.
+func firstSegment(data []byte, name string) string {
+	for _, seg := range splitSegments(data) {
+		if strings.HasPrefix(seg, name) {
+			return seg
+		}
+	}
+	return ""
+}
+
+// fieldSeparator returns the field separator declared by data's own MSH
+// segment -- the character immediately following "MSH" -- falling back to
+// the HL7 default "|" if none can be determined. Built-in transformers use
+// this instead of assuming "|" so a message declaring non-default
+// delimiters in MSH-1/MSH-2 isn't corrupted.
+func fieldSeparator(data []byte) string {
+	msh := firstSegment(data, "MSH")
+	if len(msh) < 4 {
+		return "|"
+	}
+	return string(msh[3])
+}
@@ -0,0 +1,35 @@
+// Package transform provides pluggable, composable mutations applied to a
+// raw HL7v2 message between a Source and a Sink. Transformer's Apply
+// signature matches hl7push.Transform, so any Transformer in this package
+// can be used directly in a hl7push.Pipeline's Transforms list.
+package transform
+
+// Transformer mutates a raw HL7v2 message, returning the (possibly
+// unchanged) result. Implementations work on the raw bytes rather than a
+// parsed *hl7.Message, following the same manual segment/field parsing
+// this repo already uses for dead-letter message control ID extraction --
+// go-hl7 does not expose a way to mutate and re-serialize a parsed
+// message. To still honor a message's own delimiters rather than assuming
+// the HL7 defaults, every built-in Transformer reads its field separator
+// from the message's own MSH segment (see fieldSeparator in segments.go)
+// instead of hard-coding "|".
+type Transformer interface {
+	Apply(data []byte) ([]byte, error)
+}
+
+// Chain runs a sequence of Transformers in order, feeding each one's
+// output to the next. A nil or empty Chain is a no-op.
+type Chain []Transformer
+
+// Apply satisfies Transformer, so a Chain can be nested inside another
+// Chain or used wherever a single Transformer is expected.
+func (c Chain) Apply(data []byte) ([]byte, error) {
+	for _, t := range c {
+		var err error
+		data, err = t.Apply(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,186 @@
+package transform
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// The PID segment is padded out to PID-19 (index 19) so the sample SSN
+// value lands on its real field position rather than an earlier one.
+const sampleMsg = "MSH|^~\\&|SENDAPP|SENDFAC|RECVAPP|RECVFAC|20240101120000||ADT^A01|123|P|2.3\r" +
+	"PID|1||12345||Doe^Jane||19800101|F|||||||||||987-65-4320\r"
+
+func TestChainAppliesInOrder(t *testing.T) {
+	upper := transformerFunc(func(data []byte) ([]byte, error) {
+		return []byte(strings.ToUpper(string(data))), nil
+	})
+	suffix := transformerFunc(func(data []byte) ([]byte, error) {
+		return append(data, '!'), nil
+	})
+
+	out, err := Chain{upper, suffix}.Apply([]byte("abc"))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if string(out) != "ABC!" {
+		t.Fatalf("expected ABC!, got %q", out)
+	}
+}
+
+type transformerFunc func(data []byte) ([]byte, error)
+
+func (f transformerFunc) Apply(data []byte) ([]byte, error) { return f(data) }
+
+func TestZSegmentTemplateAppendsSegment(t *testing.T) {
+	z := ZSegmentTemplate{Template: "ZAC|static-value"}
+
+	out, err := z.Apply([]byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if !strings.HasSuffix(string(out), "ZAC|static-value") {
+		t.Fatalf("expected appended Z-segment, got %q", out)
+	}
+}
+
+func TestZSegmentTemplateNoSpuriousEmptySegment(t *testing.T) {
+	z := ZSegmentTemplate{Template: "ZAC|static-value"}
+
+	out, err := z.Apply([]byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if strings.Contains(string(out), "\r\r") {
+		t.Fatalf("expected no empty segment between the message and the appended Z-segment, got %q", out)
+	}
+}
+
+func TestZSegmentTemplatePlaceholders(t *testing.T) {
+	z := ZSegmentTemplate{Template: "ZAC|{{uuid}}"}
+
+	out, err := z.Apply([]byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if strings.Contains(string(out), "{{uuid}}") {
+		t.Fatalf("expected {{uuid}} placeholder to be replaced, got %q", out)
+	}
+}
+
+func TestPIDRedactorMasksConfiguredFields(t *testing.T) {
+	r := PIDRedactor{Rules: RedactionRules{Name: true, SSN: true}}
+
+	out, err := r.Apply([]byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	pid := firstSegment(out, "PID")
+	if strings.Contains(pid, "Doe^Jane") {
+		t.Fatalf("expected name to be redacted, got %q", pid)
+	}
+	if strings.Contains(pid, "987-65-4320") {
+		t.Fatalf("expected SSN to be redacted, got %q", pid)
+	}
+	if !strings.Contains(pid, "19800101") {
+		t.Fatalf("expected DOB to be left alone, got %q", pid)
+	}
+}
+
+func TestMSHRewriterAppliesMatchingRule(t *testing.T) {
+	rw := MSHRewriter{Rules: map[string]MSHRewriteRule{
+		"SENDFAC": {
+			SendingApp:        "NEWAPP",
+			SendingFacility:   "NEWFAC",
+			ReceivingApp:      "NEWRECVAPP",
+			ReceivingFacility: "NEWRECVFAC",
+		},
+	}}
+
+	out, err := rw.Apply([]byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	msh := firstSegment(out, "MSH")
+	want := "MSH|^~\\&|NEWAPP|NEWFAC|NEWRECVAPP|NEWRECVFAC"
+	if !strings.HasPrefix(msh, want) {
+		t.Fatalf("expected rewritten MSH to start with %q, got %q", want, msh)
+	}
+}
+
+func TestMSHRewriterLeavesUnsetFieldsAlone(t *testing.T) {
+	rw := MSHRewriter{Rules: map[string]MSHRewriteRule{
+		"SENDFAC": {ReceivingFacility: "NEWRECVFAC"},
+	}}
+
+	out, err := rw.Apply([]byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	msh := firstSegment(out, "MSH")
+	want := "MSH|^~\\&|SENDAPP|SENDFAC|RECVAPP|NEWRECVFAC"
+	if !strings.HasPrefix(msh, want) {
+		t.Fatalf("expected only receiving facility rewritten, rest left alone, got %q", msh)
+	}
+}
+
+func TestMSHRewriterIgnoresUnmatchedFacility(t *testing.T) {
+	rw := MSHRewriter{Rules: map[string]MSHRewriteRule{"OTHERFAC": {SendingApp: "X"}}}
+
+	out, err := rw.Apply([]byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if string(out) != sampleMsg {
+		t.Fatalf("expected message to pass through unchanged, got %q", out)
+	}
+}
+
+type stubSink struct {
+	path string
+}
+
+func (s *stubSink) Send(ctx context.Context, data []byte) ([]byte, string, error) {
+	return nil, s.path, nil
+}
+
+func TestRouterDispatchesOnSendingFacility(t *testing.T) {
+	matched := &stubSink{path: "matched"}
+	r := Router{Routes: map[string]Sink{"SENDFAC": matched}}
+
+	_, path, err := r.Send(context.Background(), []byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if path != "matched" {
+		t.Fatalf("expected message routed to matched sink, got path %q", path)
+	}
+}
+
+func TestRouterFallsBackToDefault(t *testing.T) {
+	def := &stubSink{path: "default"}
+	r := Router{Routes: map[string]Sink{}, Default: def}
+
+	_, path, err := r.Send(context.Background(), []byte(sampleMsg))
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if path != "default" {
+		t.Fatalf("expected message routed to default sink, got path %q", path)
+	}
+}
+
+func TestRouterErrorsWithNoMatchAndNoDefault(t *testing.T) {
+	r := Router{Routes: map[string]Sink{}}
+
+	if _, _, err := r.Send(context.Background(), []byte(sampleMsg)); err == nil {
+		t.Fatal("expected an error when no route and no default sink are configured")
+	}
+}
@@ -0,0 +1,66 @@
+package transform
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// ZSegmentTemplate appends a Z-segment built from Template, a
+// pipe-delimited HL7 segment string. Template supports the placeholders:
+//
+//	{{time}}      current time, formatted per TimeFormat (default
+//	              20060102150405)
+//	{{uuid}}      a randomly generated UUID
+//	{{env:NAME}}  the value of environment variable NAME
+//
+// This replaces the hard-coded "ZAC|<timestamp>" append hl7push used
+// before transforms existed.
+type ZSegmentTemplate struct {
+	Template   string
+	TimeFormat string
+}
+
+func (z ZSegmentTemplate) Apply(data []byte) ([]byte, error) {
+	format := z.TimeFormat
+	if format == "" {
+		format = "20060102150405"
+	}
+
+	segment := z.Template
+	segment = strings.ReplaceAll(segment, "{{time}}", time.Now().Format(format))
+	segment = strings.ReplaceAll(segment, "{{uuid}}", newUUID())
+	segment = replaceEnvPlaceholders(segment)
+
+	segments := splitSegments(data)
+	// data ends in a \r terminator, so splitSegments' last element is an
+	// empty trailing segment; drop it before appending so the result isn't
+	// "...\r\rZAC|..." with a spurious empty segment in between.
+	if n := len(segments); n > 0 && segments[n-1] == "" {
+		segments = segments[:n-1]
+	}
+	segments = append(segments, segment)
+
+	return joinSegments(segments), nil
+}
+
+func replaceEnvPlaceholders(s string) string {
+	const prefix = "{{env:"
+	const suffix = "}}"
+
+	for {
+		start := strings.Index(s, prefix)
+		if start == -1 {
+			return s
+		}
+
+		end := strings.Index(s[start:], suffix)
+		if end == -1 {
+			return s
+		}
+		end += start
+
+		name := s[start+len(prefix) : end]
+		s = s[:start] + os.Getenv(name) + s[end+len(suffix):]
+	}
+}
@@ -0,0 +1,217 @@
+// Package watcher implements an fsnotify-based directory watcher that
+// enqueues newly created `.hl7` files onto a buffered channel drained by a
+// pool of worker goroutines, replacing fixed-count polling of the target
+// directory.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Handler processes a single discovered file. Workers call it once per
+// dispatched path, passing Run's ctx through so a canceled shutdown
+// context can abort an in-flight send.
+type Handler func(ctx context.Context, dir, name string) error
+
+// Config controls the watcher's concurrency and its file-stability checks.
+type Config struct {
+	// Workers is the number of worker goroutines draining the queue.
+	// Defaults to runtime.GOMAXPROCS(0) when <= 0.
+	Workers int
+	// QueueSize bounds the buffered channel between the fsnotify watcher
+	// and the worker pool. Defaults to 64 when <= 0.
+	QueueSize int
+	// StabilityCheck is the delay between the two stat calls used to
+	// confirm a file's size is no longer changing before it is
+	// dispatched. Defaults to 250ms when <= 0.
+	StabilityCheck time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = runtime.GOMAXPROCS(0)
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 64
+	}
+	if c.StabilityCheck <= 0 {
+		c.StabilityCheck = 250 * time.Millisecond
+	}
+	return c
+}
+
+// Watcher observes Root, and every subdirectory discovered under it, for
+// new `.hl7` files and hands each one to a pool of worker goroutines
+// running Handle.
+type Watcher struct {
+	root   string
+	handle Handler
+	config Config
+
+	fsw   *fsnotify.Watcher
+	queue chan string
+}
+
+// New creates a Watcher rooted at root. Call Run to start watching; it
+// does not return until ctx is canceled or an unrecoverable error occurs.
+func New(root string, handle Handler, cfg Config) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		root:   root,
+		handle: handle,
+		config: cfg.withDefaults(),
+		fsw:    fsw,
+		queue:  make(chan string, cfg.withDefaults().QueueSize),
+	}, nil
+}
+
+// Run watches root (and subdirectories) until ctx is canceled, dispatching
+// each discovered file to the worker pool. It blocks until every worker has
+// returned. Workers stop taking files from the queue once ctx is canceled,
+// so files still queued at that point may be left unhandled.
+func (w *Watcher) Run(ctx context.Context) error {
+	if err := w.addRecursive(w.root); err != nil {
+		w.fsw.Close()
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work(ctx)
+		}()
+	}
+
+	err := w.watch(ctx)
+
+	close(w.queue)
+	wg.Wait()
+	w.fsw.Close()
+	return err
+}
+
+// addRecursive registers path and every subdirectory under it with the
+// underlying fsnotify watcher.
+func (w *Watcher) addRecursive(path string) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return w.fsw.Add(p)
+		}
+		return nil
+	})
+}
+
+// watch drains fsnotify events until ctx is canceled, adding newly created
+// subdirectories to the watch set and enqueuing newly created `.hl7` files.
+func (w *Watcher) watch(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&fsnotify.Create == 0 {
+				continue
+			}
+
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				if err := w.addRecursive(event.Name); err != nil {
+					fmt.Printf("watcher: failed to watch new directory %s: %v\n", event.Name, err)
+				}
+				continue
+			}
+			if strings.Contains(info.Name(), ".hl7") {
+				w.enqueue(ctx, event.Name)
+			}
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (w *Watcher) enqueue(ctx context.Context, path string) {
+	select {
+	case w.queue <- path:
+	case <-ctx.Done():
+	}
+}
+
+// work drains the queue, waiting for each file to stop growing before
+// calling Handle on it.
+func (w *Watcher) work(ctx context.Context) {
+	for {
+		select {
+		case path, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			if err := waitStable(ctx, path, w.config.StabilityCheck); err != nil {
+				fmt.Printf("watcher: giving up on %s: %v\n", path, err)
+				continue
+			}
+			dir, name := filepath.Split(path)
+			if err := w.handle(ctx, strings.TrimSuffix(dir, "/"), name); err != nil {
+				fmt.Printf("error: %s\n", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// waitStable blocks until path's size is unchanged across two stat calls
+// separated by interval, working around the race where a watcher fires on
+// Create before the writer has finished writing the file.
+func waitStable(ctx context.Context, path string, interval time.Duration) error {
+	prev, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		cur, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if cur.Size() == prev.Size() {
+			return nil
+		}
+		prev = cur
+	}
+}
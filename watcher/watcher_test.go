@@ -0,0 +1,63 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcherDispatchesNewFile(t *testing.T) {
+	dir := t.TempDir()
+
+	var mu sync.Mutex
+	var seen []string
+
+	w, err := New(dir, func(ctx context.Context, d, name string) error {
+		mu.Lock()
+		seen = append(seen, name)
+		mu.Unlock()
+		return nil
+	}, Config{Workers: 1, StabilityCheck: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	// give the watcher a moment to register the root directory
+	time.Sleep(50 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(dir, "msg1.hl7"), []byte("MSH|..."), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(seen)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			cancel()
+			t.Fatal("timed out waiting for watcher to dispatch new file")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 1 || seen[0] != "msg1.hl7" {
+		t.Fatalf("unexpected dispatched files: %v", seen)
+	}
+}